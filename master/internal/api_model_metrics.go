@@ -2,14 +2,50 @@ package internal
 
 import (
 	"context"
+	"io"
+	"strings"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	"github.com/determined-ai/determined/master/internal/db"
 	"github.com/determined-ai/determined/master/pkg/model"
 	"github.com/determined-ai/determined/proto/pkg/apiv1"
 )
 
+// metricsFromProtoError turns a model.TrainingMetricsFromProto/ValidationMetricsFromProto
+// failure into a gRPC error the caller can act on. A *model.MetricsValidationError is always
+// the client's fault, so it is surfaced as codes.InvalidArgument with one google.rpc.BadRequest
+// field violation per FieldError: a trial harness or the CLI can read the field names back out
+// and fix its submission instead of guessing at an opaque message. Any other error is treated
+// as a server-side fault and wrapped the same way a DB error from this file already is.
+func metricsFromProtoError(err error, format string, args ...interface{}) error {
+	var validationErr *model.MetricsValidationError
+	if !errors.As(err, &validationErr) {
+		return errors.Wrapf(err, format, args...)
+	}
+
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(validationErr.Errors))
+	for _, fieldErr := range validationErr.Errors {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       fieldErr.FieldName,
+			Description: fieldErr.Reason,
+		})
+	}
+
+	st, detailErr := status.New(codes.InvalidArgument, errors.Wrapf(err, format, args...).Error()).
+		WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if detailErr != nil {
+		// Attaching details should never fail for a well-formed BadRequest; fall back to the
+		// plain status rather than dropping the validation error entirely.
+		return status.Error(codes.InvalidArgument, errors.Wrapf(err, format, args...).Error())
+	}
+	return st.Err()
+}
+
 func (a *apiServer) CreateTrainingMetrics(
 	_ context.Context, req *apiv1.CreateTrainingMetricsRequest,
 ) (*apiv1.CreateTrainingMetricsResponse, error) {
@@ -18,12 +54,15 @@ func (a *apiServer) CreateTrainingMetrics(
 		req.TrainingMetrics.StartBatch, req.TrainingMetrics.EndBatch)
 	modelT, err := model.TrainingMetricsFromProto(req.TrainingMetrics)
 	if err != nil {
-		return nil, errors.Wrapf(
-			err, "error adding training metrics %s (trial %d, batch %d to %d) in database",
+		return nil, metricsFromProtoError(
+			err, "error adding training metrics %s (trial %d, batch %d to %d)",
 			req.TrainingMetrics.Uuid, req.TrainingMetrics.TrialId,
 			req.TrainingMetrics.StartBatch, req.TrainingMetrics.EndBatch)
 	}
 	err = a.m.db.AddStep(modelT)
+	if err == nil {
+		a.observePromMetrics(int(req.TrainingMetrics.TrialId), req.TrainingMetrics.Metrics)
+	}
 	return &apiv1.CreateTrainingMetricsResponse{TrainingMetrics: req.TrainingMetrics},
 		errors.Wrapf(err,
 			"error adding training metrics %s (trial %d, batch %d to %d) in database",
@@ -39,8 +78,8 @@ func (a *apiServer) UpdateTrainingMetrics(
 		req.TrainingMetrics.StartBatch, req.TrainingMetrics.EndBatch, req.TrainingMetrics.State)
 	modelS, err := model.TrainingMetricsFromProto(req.TrainingMetrics)
 	if err != nil {
-		return nil, errors.Wrapf(
-			err, "error updating training metrics %s (trial %d, batch %d to %d) in database",
+		return nil, metricsFromProtoError(
+			err, "error updating training metrics %s (trial %d, batch %d to %d)",
 			req.TrainingMetrics.Uuid, req.TrainingMetrics.TrialId,
 			req.TrainingMetrics.StartBatch, req.TrainingMetrics.EndBatch)
 	}
@@ -60,12 +99,18 @@ func (a *apiServer) CreateValidationMetrics(
 		req.ValidationMetrics.Uuid, req.ValidationMetrics.TrialId, req.ValidationMetrics.TotalBatches)
 	modelV, err := model.ValidationMetricsFromProto(req.ValidationMetrics)
 	if err != nil {
-		return nil, errors.Wrapf(
-			err, "error adding validation metrics %s (trial %d, batch %d) in database",
+		return nil, metricsFromProtoError(
+			err, "error adding validation metrics %s (trial %d, batch %d)",
 			req.ValidationMetrics.Uuid, req.ValidationMetrics.TrialId,
 			req.ValidationMetrics.TotalBatches)
 	}
 	err = a.m.db.AddValidation(modelV)
+	if err == nil {
+		a.observePromMetrics(int(req.ValidationMetrics.TrialId), req.ValidationMetrics.Metrics)
+		a.invokeSearcherHook(
+			int(req.ValidationMetrics.TrialId), int(req.ValidationMetrics.TotalBatches),
+			req.ValidationMetrics.Metrics)
+	}
 	return &apiv1.CreateValidationMetricsResponse{ValidationMetrics: req.ValidationMetrics},
 		errors.Wrapf(err, "error adding validation metrics %s (trial %d, batch %d) in database",
 			req.ValidationMetrics.Uuid, req.ValidationMetrics.TrialId,
@@ -80,8 +125,8 @@ func (a *apiServer) UpdateValidationMetrics(
 		req.ValidationMetrics.TotalBatches, req.ValidationMetrics.State)
 	modelV, err := model.ValidationMetricsFromProto(req.ValidationMetrics)
 	if err != nil {
-		return nil, errors.Wrapf(
-			err, "error updating validation metrics %s (trial %d, batch %d) in database",
+		return nil, metricsFromProtoError(
+			err, "error updating validation metrics %s (trial %d, batch %d)",
 			req.ValidationMetrics.Uuid, req.ValidationMetrics.TrialId,
 			req.ValidationMetrics.TotalBatches)
 	}
@@ -93,3 +138,156 @@ func (a *apiServer) UpdateValidationMetrics(
 			req.ValidationMetrics.Uuid, req.ValidationMetrics.TrialId,
 			req.ValidationMetrics.TotalBatches)
 }
+
+// CreateMetricsBatch ingests a batch of training and validation metric records in a single
+// RPC, persisting them in one DB transaction. Trial harnesses on short-step experiments
+// otherwise issue one RPC per step, which dominates master CPU; batching lets a harness send
+// tens to hundreds of steps at once.
+//
+// Each record carries a client-generated UUID that doubles as an idempotency key: if a record
+// with that UUID was already persisted by an earlier, partially-failed attempt, the prior
+// stored record is returned rather than re-inserted or rejected as a conflict. This makes the
+// whole batch safe to retry as-is rather than requiring the caller to diff out what already
+// landed.
+func (a *apiServer) CreateMetricsBatch(
+	_ context.Context, req *apiv1.CreateMetricsBatchRequest,
+) (*apiv1.CreateMetricsBatchResponse, error) {
+	log.Infof("adding metrics batch of %d records", len(req.Records))
+
+	results := make([]*apiv1.MetricsBatchRecordResult, len(req.Records))
+	err := a.m.db.WithTransaction("add metrics batch", func(tx *db.Tx) error {
+		for i, record := range req.Records {
+			results[i] = addMetricsBatchRecord(tx, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error adding metrics batch in database")
+	}
+
+	return &apiv1.CreateMetricsBatchResponse{Results: results}, nil
+}
+
+// CreateMetricsBatchStream is the streaming counterpart of CreateMetricsBatch: a trial harness
+// opens one long-lived stream for the life of a trial and sends a MetricsBatchRecord as each
+// step completes, rather than opening a unary RPC per batch. Every record sent on the stream
+// goes through the same idempotency and transactional handling as CreateMetricsBatch, with one
+// result streamed back per record so the harness can apply backpressure and retry individual
+// records without reopening the stream.
+func (a *apiServer) CreateMetricsBatchStream(
+	stream apiv1.Determined_CreateMetricsBatchStreamServer,
+) error {
+	for {
+		record, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			// The client half-closed the stream after sending its last record; that's a
+			// normal end-of-batch, not a failure.
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "error receiving metrics batch record from stream")
+		}
+
+		var result *apiv1.MetricsBatchRecordResult
+		txErr := a.m.db.WithTransaction("add metrics batch record", func(tx *db.Tx) error {
+			result = addMetricsBatchRecord(tx, record)
+			return nil
+		})
+		if txErr != nil {
+			return errors.Wrap(txErr, "error adding metrics batch record in database")
+		}
+
+		if err = stream.Send(result); err != nil {
+			return errors.Wrap(err, "error sending metrics batch result to stream")
+		}
+	}
+}
+
+// addMetricsBatchRecord persists a single record of a metrics batch and classifies the outcome
+// rather than letting a bad or duplicate record fail the surrounding transaction. Both the
+// duplicate-UUID lookup and the insert run inside one savepoint per record: Postgres aborts an
+// entire transaction on the first statement error, including a failed read, so without a
+// savepoint around the lookup too, one record's lookup error would poison the transaction and
+// spuriously fail every subsequent record's insert. A savepoint rollback undoes only that
+// record's work and leaves the outer transaction (and the records already committed to it)
+// intact, so the caller's per-record result accurately reflects what is retryable.
+func addMetricsBatchRecord(
+	tx *db.Tx, record *apiv1.MetricsBatchRecord,
+) *apiv1.MetricsBatchRecordResult {
+	result := &apiv1.MetricsBatchRecordResult{Uuid: record.Uuid}
+
+	var modelT *model.TrainingMetrics
+	var modelV *model.ValidationMetrics
+	switch {
+	case record.TrainingMetrics != nil:
+		var err error
+		if modelT, err = model.TrainingMetricsFromProto(record.TrainingMetrics); err != nil {
+			result.Status = apiv1.MetricsBatchRecordResult_STATUS_FAILED
+			result.Retryable = false
+			result.Error = err.Error()
+			return result
+		}
+	case record.ValidationMetrics != nil:
+		var err error
+		if modelV, err = model.ValidationMetricsFromProto(record.ValidationMetrics); err != nil {
+			result.Status = apiv1.MetricsBatchRecordResult_STATUS_FAILED
+			result.Retryable = false
+			result.Error = err.Error()
+			return result
+		}
+	default:
+		result.Status = apiv1.MetricsBatchRecordResult_STATUS_FAILED
+		result.Retryable = false
+		result.Error = "metrics batch record has neither training nor validation metrics set"
+		return result
+	}
+
+	savepointErr := tx.WithSavepoint(savepointName(record.Uuid), func(sp *db.Tx) error {
+		prior, err := sp.MetricsRecordByUUID(record.Uuid)
+		switch {
+		case err == nil:
+			result.Status = apiv1.MetricsBatchRecordResult_STATUS_DUPLICATE
+			result.TrainingMetrics = prior.TrainingMetrics
+			result.ValidationMetrics = prior.ValidationMetrics
+			return nil
+		case !errors.Is(err, db.ErrNotFound):
+			return err
+		case modelT != nil:
+			return sp.AddStep(modelT)
+		default:
+			return sp.AddValidation(modelV)
+		}
+	})
+	if savepointErr != nil {
+		result.Status = apiv1.MetricsBatchRecordResult_STATUS_FAILED
+		result.Retryable = db.IsRetryable(savepointErr)
+		result.Error = savepointErr.Error()
+		return result
+	}
+	if result.Status == apiv1.MetricsBatchRecordResult_STATUS_DUPLICATE {
+		return result
+	}
+
+	result.Status = apiv1.MetricsBatchRecordResult_STATUS_OK
+	result.TrainingMetrics = record.TrainingMetrics
+	result.ValidationMetrics = record.ValidationMetrics
+	return result
+}
+
+// savepointName derives a SQL-identifier-safe savepoint name from a record's client-generated
+// UUID: it's interpolated directly rather than passed as a bind parameter (Postgres doesn't
+// support parameterized identifiers for SAVEPOINT), so anything other than letters, digits,
+// and underscores is replaced rather than relying on WithSavepoint to quote it.
+func savepointName(recordUUID string) string {
+	var b strings.Builder
+	b.WriteString("metrics_batch_")
+	for _, r := range recordUUID {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/determined-ai/determined/master/internal/prom"
+)
+
+// ConfigurePromMetrics should be called once during master startup, after the master's HTTP
+// router is available, to size the registry's cardinality cap, wire up an optional InfluxDB
+// pusher, and register the /prom/metrics scrape route. Masters that skip it still get metrics
+// mirroring through promMetricsRegistry's lazily-built default registry; they just don't get a
+// custom cap, an InfluxDB pusher, or the HTTP route.
+func (m *Master) ConfigurePromMetrics(maxLabelSets int, influx prom.InfluxPusher, mux *http.ServeMux) {
+	m.promMetrics = prom.NewMetricsRegistry(maxLabelSets, influx)
+	mux.Handle("/prom/metrics", promhttp.HandlerFor(m.promMetrics.Gatherer(), promhttp.HandlerOpts{}))
+}
+
+// promMetricsRegistry returns m's Prometheus registry, lazily building one with default
+// settings on first use if ConfigurePromMetrics was never called, so a metric is always
+// mirrored somewhere instead of silently dropped when startup wiring is incomplete.
+func (m *Master) promMetricsRegistry() *prom.MetricsRegistry {
+	m.promMetricsOnce.Do(func() {
+		if m.promMetrics == nil {
+			m.promMetrics = prom.NewMetricsRegistry(prom.DefaultMaxLabelSets, nil)
+		}
+	})
+	return m.promMetrics
+}
+
+// observePromMetrics mirrors a successfully ingested metric record into the master's
+// in-process Prometheus registry (see internal/prom) so that an existing Grafana/Prometheus
+// stack can scrape /prom/metrics instead of polling the database. A missing experiment ID on
+// the trial is logged and skipped rather than failing the RPC, since Prometheus mirroring is
+// best-effort and must never block metric ingestion.
+func (a *apiServer) observePromMetrics(trialID int, metrics map[string]float64) {
+	experimentID, err := a.m.db.ExperimentIDByTrialID(trialID)
+	if err != nil {
+		log.Warnf("could not resolve experiment for trial %d for prometheus metrics: %v",
+			trialID, err)
+		return
+	}
+
+	registry := a.m.promMetricsRegistry()
+	for name, value := range metrics {
+		registry.Observe(experimentID, trialID, name, value)
+	}
+}
@@ -0,0 +1,202 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/proto/pkg/apiv1"
+)
+
+// maxMetricsPointsPerSeries bounds how many points GetTrainingMetrics/GetValidationMetrics
+// return per series before downsampling kicks in. StreamMetrics applies the same cap per
+// flushed chunk so a long-running trial can't push an unbounded response.
+const maxMetricsPointsPerSeries = 1000
+
+// GetTrainingMetrics reads back a trial's training metric series, optionally filtered to a
+// batch range, a metric name, or a time window, and downsampled with min/max bucketing when
+// the raw series exceeds maxMetricsPointsPerSeries. This is the read-side counterpart of
+// CreateTrainingMetrics/UpdateTrainingMetrics: WebUI charts and external dashboards use it to
+// stream metrics directly instead of going through internal DB queries.
+func (a *apiServer) GetTrainingMetrics(
+	_ context.Context, req *apiv1.GetTrainingMetricsRequest,
+) (*apiv1.GetTrainingMetricsResponse, error) {
+	log.Infof("fetching training metrics for trial %d", req.TrialId)
+
+	series, err := a.m.db.TrainingMetricsSeries(int(req.TrialId), db.MetricsSeriesFilter{
+		MetricName: req.MetricName,
+		StartBatch: req.StartBatch,
+		EndBatch:   req.EndBatch,
+		StartTime:  req.StartTime,
+		EndTime:    req.EndTime,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error fetching training metrics for trial %d", req.TrialId)
+	}
+
+	return &apiv1.GetTrainingMetricsResponse{
+		Metrics: downsampleMetrics(series, maxMetricsPointsPerSeries),
+	}, nil
+}
+
+// GetValidationMetrics is the GetTrainingMetrics counterpart for validation metrics.
+func (a *apiServer) GetValidationMetrics(
+	_ context.Context, req *apiv1.GetValidationMetricsRequest,
+) (*apiv1.GetValidationMetricsResponse, error) {
+	log.Infof("fetching validation metrics for trial %d", req.TrialId)
+
+	series, err := a.m.db.ValidationMetricsSeries(int(req.TrialId), db.MetricsSeriesFilter{
+		MetricName: req.MetricName,
+		StartBatch: req.StartBatch,
+		EndBatch:   req.EndBatch,
+		StartTime:  req.StartTime,
+		EndTime:    req.EndTime,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error fetching validation metrics for trial %d", req.TrialId)
+	}
+
+	return &apiv1.GetValidationMetricsResponse{
+		Metrics: downsampleMetrics(series, maxMetricsPointsPerSeries),
+	}, nil
+}
+
+// StreamMetrics streams a trial's training and validation metrics as they are written, so a
+// client can watch a live experiment the same way it would read back a finished one. It first
+// replays any metrics already persisted (downsampled, like GetTrainingMetrics/
+// GetValidationMetrics), then forwards new metrics as CreateTrainingMetrics/
+// CreateValidationMetrics land, until the trial finishes or the client disconnects.
+func (a *apiServer) StreamMetrics(
+	req *apiv1.StreamMetricsRequest, stream apiv1.Determined_StreamMetricsServer,
+) error {
+	log.Infof("streaming metrics for trial %d", req.TrialId)
+
+	filter := db.MetricsSeriesFilter{MetricName: req.MetricName}
+	training, err := a.m.db.TrainingMetricsSeries(int(req.TrialId), filter)
+	if err != nil {
+		return errors.Wrapf(err, "error fetching training metrics for trial %d", req.TrialId)
+	}
+	validation, err := a.m.db.ValidationMetricsSeries(int(req.TrialId), filter)
+	if err != nil {
+		return errors.Wrapf(err, "error fetching validation metrics for trial %d", req.TrialId)
+	}
+
+	for _, chunk := range chunkMetrics(downsampleMetrics(training, maxMetricsPointsPerSeries),
+		downsampleMetrics(validation, maxMetricsPointsPerSeries), maxMetricsPointsPerSeries) {
+		if err := stream.Send(chunk); err != nil {
+			return errors.Wrap(err, "error sending metrics chunk to stream")
+		}
+	}
+
+	updates := a.m.db.WatchMetrics(int(req.TrialId))
+	defer a.m.db.UnwatchMetrics(int(req.TrialId), updates)
+	for update := range updates {
+		// WatchMetrics is not itself filtered by metric name, so a client that asked for one
+		// metric would otherwise start seeing every metric on the trial once it catches up to
+		// live updates, even though the replay above only sent the one it asked for.
+		filtered := filterMetricName(update, req.MetricName)
+		if filtered == nil {
+			continue
+		}
+		if err := stream.Send(filtered); err != nil {
+			return errors.Wrap(err, "error sending metrics update to stream")
+		}
+	}
+	return nil
+}
+
+// filterMetricName restricts a live metrics update to points matching metricName, returning
+// the update unchanged if metricName is empty (no filter requested) or nil if nothing in the
+// update survives the filter.
+func filterMetricName(
+	update *apiv1.StreamMetricsResponse, metricName string,
+) *apiv1.StreamMetricsResponse {
+	if metricName == "" {
+		return update
+	}
+
+	training := filterPointsByName(update.Training, metricName)
+	validation := filterPointsByName(update.Validation, metricName)
+	if len(training) == 0 && len(validation) == 0 {
+		return nil
+	}
+	return &apiv1.StreamMetricsResponse{Training: training, Validation: validation}
+}
+
+func filterPointsByName(points []*apiv1.MetricsPoint, metricName string) []*apiv1.MetricsPoint {
+	filtered := make([]*apiv1.MetricsPoint, 0, len(points))
+	for _, p := range points {
+		if p.MetricName == metricName {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+// downsampleMetrics reduces a metric series to at most maxPoints using min/max bucketing: the
+// series is split into maxPoints/2 buckets and each contributes its min and max point, which
+// preserves spikes that a naive stride sample would average away. Series already at or under
+// the cap, and any series when maxPoints is below 2, are returned unchanged.
+func downsampleMetrics(
+	series []*apiv1.MetricsPoint, maxPoints int,
+) []*apiv1.MetricsPoint {
+	if len(series) <= maxPoints || maxPoints < 2 {
+		return series
+	}
+
+	buckets := maxPoints / 2
+	bucketSize := (len(series) + buckets - 1) / buckets
+	out := make([]*apiv1.MetricsPoint, 0, maxPoints)
+	for start := 0; start < len(series); start += bucketSize {
+		end := start + bucketSize
+		if end > len(series) {
+			end = len(series)
+		}
+		bucket := series[start:end]
+		min, max := bucket[0], bucket[0]
+		for _, p := range bucket {
+			if p.Value < min.Value {
+				min = p
+			}
+			if p.Value > max.Value {
+				max = p
+			}
+		}
+		if min == max {
+			// A bucket of size 1, or a flat region, has the same point as both its min and
+			// max; emit it once instead of duplicating it in the downsampled series.
+			out = append(out, min)
+		} else if min.Batch <= max.Batch {
+			out = append(out, min, max)
+		} else {
+			out = append(out, max, min)
+		}
+	}
+	return out
+}
+
+// chunkMetrics splits the replay of a trial's existing training and validation series into
+// stream-sized chunks so StreamMetrics doesn't buffer an entire trial's history into one
+// message before the client sees anything.
+func chunkMetrics(
+	training, validation []*apiv1.MetricsPoint, chunkSize int,
+) []*apiv1.StreamMetricsResponse {
+	var chunks []*apiv1.StreamMetricsResponse
+	for start := 0; start < len(training); start += chunkSize {
+		end := start + chunkSize
+		if end > len(training) {
+			end = len(training)
+		}
+		chunks = append(chunks, &apiv1.StreamMetricsResponse{Training: training[start:end]})
+	}
+	for start := 0; start < len(validation); start += chunkSize {
+		end := start + chunkSize
+		if end > len(validation) {
+			end = len(validation)
+		}
+		chunks = append(chunks, &apiv1.StreamMetricsResponse{Validation: validation[start:end]})
+	}
+	return chunks
+}
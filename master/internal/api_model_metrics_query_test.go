@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/proto/pkg/apiv1"
+)
+
+func metricsPoints(values ...float64) []*apiv1.MetricsPoint {
+	points := make([]*apiv1.MetricsPoint, len(values))
+	for i, v := range values {
+		points[i] = &apiv1.MetricsPoint{Batch: int32(i), Value: v}
+	}
+	return points
+}
+
+func TestDownsampleMetricsUnderCap(t *testing.T) {
+	series := metricsPoints(1, 2, 3)
+	out := downsampleMetrics(series, 10)
+	assert.Equal(t, series, out)
+}
+
+func TestDownsampleMetricsAtCap(t *testing.T) {
+	series := metricsPoints(1, 2)
+	out := downsampleMetrics(series, 2)
+	assert.Equal(t, series, out)
+}
+
+func TestDownsampleMetricsOverCapKeepsSpikes(t *testing.T) {
+	// Two buckets of four points each; every bucket has a spike in each direction that a
+	// stride sample would skip over, so each bucket's min and max must survive.
+	series := metricsPoints(0, 10, 0, -10, 0, 10, 0, -10)
+	out := downsampleMetrics(series, 4)
+
+	require.Len(t, out, 4)
+	for _, p := range out {
+		assert.Contains(t, []float64{10, -10}, p.Value)
+	}
+}
+
+func TestDownsampleMetricsPreservesBatchOrder(t *testing.T) {
+	series := metricsPoints(5, -5, 5, -5, 5, -5, 5, -5)
+	out := downsampleMetrics(series, 4)
+
+	for i := 1; i < len(out); i++ {
+		assert.LessOrEqual(t, out[i-1].Batch, out[i].Batch)
+	}
+}
+
+func TestDownsampleMetricsDedupesFlatBucket(t *testing.T) {
+	// A flat region has the same point as both its bucket's min and max; it must only appear
+	// once in the downsampled output, not twice.
+	series := metricsPoints(1, 1, 1, 1, 1, 1)
+	out := downsampleMetrics(series, 2)
+
+	require.Len(t, out, 1)
+	assert.Equal(t, series[0], out[0])
+}
+
+func TestDownsampleMetricsSingletonBucketNotDuplicated(t *testing.T) {
+	// Seven points with a cap of 6 make three buckets of three, the last holding only the
+	// final point; it must appear once in the output, not as both its bucket's min and max.
+	series := metricsPoints(1, 2, 3, 4, 5, 6, 7)
+	out := downsampleMetrics(series, 6)
+	require.Len(t, out, 5)
+	assert.Equal(t, series[6], out[4])
+}
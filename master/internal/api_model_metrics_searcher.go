@@ -0,0 +1,66 @@
+package internal
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/determined-ai/determined/master/internal/searcher"
+)
+
+// invokeSearcherHook runs the experiment's opted-in searcher hook, if any, synchronously after
+// a validation has been persisted, and applies the action it returns. This generalizes the
+// searcher's own poll-the-database loop for ASHA/PBT/median-stopping into the ingestion hot
+// path: a validation write now drives the stop/promote decision directly instead of waiting
+// for the searcher's next poll.
+//
+// A hook is only consulted when the experiment's searcher_hooks config field opted in; by
+// default nothing changes. A hook error is logged and treated as Continue rather than failing
+// the CreateValidationMetrics RPC, since a flaky or misconfigured hook must never block metric
+// ingestion for the trial.
+func (a *apiServer) invokeSearcherHook(trialID, totalBatches int, metrics map[string]float64) {
+	hookName, metricName, ok := a.m.db.SearcherHookConfig(trialID)
+	if !ok {
+		return
+	}
+
+	hook, ok := a.m.searcherHookFor(hookName)
+	if !ok {
+		log.Warnf("trial %d opted into unknown searcher hook %q", trialID, hookName)
+		return
+	}
+
+	value, ok := metrics[metricName]
+	if !ok {
+		log.Warnf("trial %d validation is missing configured searcher metric %q",
+			trialID, metricName)
+		return
+	}
+
+	action, err := hook.OnValidation(trialID, totalBatches, value)
+	if err != nil {
+		log.Warnf("searcher hook %q for trial %d returned an error, continuing: %v",
+			hookName, trialID, err)
+		return
+	}
+
+	a.applySearcherAction(trialID, action)
+}
+
+// applySearcherAction carries out the decision a searcher hook made for a trial. PromoteCheckpoint
+// is recorded for the searcher to pick up on its next decision point; EarlyStop is written
+// immediately so the trial runner picks it up the same way it already does for a
+// searcher-initiated stop.
+func (a *apiServer) applySearcherAction(trialID int, action searcher.Action) {
+	log.Infof("searcher hook decided trial %d should %s", trialID, action)
+
+	switch action {
+	case searcher.Continue:
+	case searcher.EarlyStop:
+		if err := a.m.db.MarkTrialForEarlyStop(trialID); err != nil {
+			log.Warnf("error marking trial %d for early stop: %v", trialID, err)
+		}
+	case searcher.PromoteCheckpoint:
+		if err := a.m.db.MarkTrialCheckpointPromoted(trialID); err != nil {
+			log.Warnf("error marking trial %d checkpoint as promoted: %v", trialID, err)
+		}
+	}
+}
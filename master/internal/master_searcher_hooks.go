@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"sync"
+
+	"github.com/determined-ai/determined/master/internal/searcher"
+)
+
+// searcherHooks holds the named searcher hooks registered at master startup; which hook, if
+// any, an experiment opted into is not held here but read per trial. Experiments name a hook by
+// the same string passed to RegisterSearcherHook (e.g. "median_stopping", or a cluster's own
+// name for a configured webhook) via their searcher_hooks config field; experiments that don't
+// set it get no hook at all, preserving today's poll-based searcher behavior.
+//
+// The zero value is ready to use: byName is lazily allocated under mu rather than requiring a
+// constructor, so a *Master that never explicitly initializes its searcherHooks field (e.g. in
+// a test, or before startup wiring runs) still behaves correctly instead of panicking on a nil
+// map.
+type searcherHooks struct {
+	mu     sync.RWMutex
+	byName map[string]searcher.Hook
+}
+
+// RegisterSearcherHook makes a searcher.Hook available under name for experiments to opt into.
+// Call it during master startup, once per configured hook; registering two hooks under the
+// same name replaces the first.
+func (m *Master) RegisterSearcherHook(name string, hook searcher.Hook) {
+	m.searcherHooks.mu.Lock()
+	defer m.searcherHooks.mu.Unlock()
+	if m.searcherHooks.byName == nil {
+		m.searcherHooks.byName = make(map[string]searcher.Hook)
+	}
+	m.searcherHooks.byName[name] = hook
+}
+
+// searcherHookFor looks up the hook an experiment opted into by name, if any.
+func (m *Master) searcherHookFor(name string) (searcher.Hook, bool) {
+	m.searcherHooks.mu.RLock()
+	defer m.searcherHooks.mu.RUnlock()
+	hook, ok := m.searcherHooks.byName[name]
+	return hook, ok
+}
@@ -0,0 +1,153 @@
+// Package prom mirrors ingested trial metrics into an in-process Prometheus registry so that
+// an existing Grafana/Prometheus stack can scrape Determined directly, without polling the
+// master's database. It also supports pushing the same samples to an InfluxDB line-protocol
+// endpoint for sites that prefer a push-based TSDB over pull scraping.
+package prom
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultMaxLabelSets bounds how many distinct (experiment_id, trial_id, metric_name) label
+// sets the registry will track at once. Long-running clusters with many short experiments
+// would otherwise grow the registry without bound; once the cap is hit, the oldest label set
+// (by last write) is evicted to make room for the new one.
+const DefaultMaxLabelSets = 10000
+
+// InfluxPusher pushes a single sample in InfluxDB line-protocol format. Implementations are
+// expected to batch and retry internally; MetricsRegistry treats a push failure as
+// best-effort and only logs it, since Prometheus scraping remains the source of truth.
+type InfluxPusher interface {
+	Push(line string) error
+}
+
+// MetricsRegistry holds the Prometheus gauges that mirror trial training/validation metrics as
+// they are ingested through CreateTrainingMetrics/CreateValidationMetrics. It is safe for
+// concurrent use.
+type MetricsRegistry struct {
+	mu           sync.Mutex
+	registry     *prometheus.Registry
+	gauge        *prometheus.GaugeVec
+	maxLabelSets int
+	influx       InfluxPusher
+
+	// lru and index implement the cardinality cap: lru orders label sets from
+	// least-recently-written (front) to most-recently-written (back), and index maps a label
+	// set's cache key to its position in lru so both lookup and eviction are O(1).
+	lru   *list.List
+	index map[string]*list.Element
+}
+
+// NewMetricsRegistry builds a MetricsRegistry with up to maxLabelSets tracked label sets. A
+// maxLabelSets of 0 selects DefaultMaxLabelSets. Pass a non-nil influx to additionally push
+// every sample to an InfluxDB line-protocol endpoint; pass nil to scrape-only.
+func NewMetricsRegistry(maxLabelSets int, influx InfluxPusher) *MetricsRegistry {
+	if maxLabelSets <= 0 {
+		maxLabelSets = DefaultMaxLabelSets
+	}
+
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "determined",
+		Name:      "trial_metric",
+		Help:      "Latest value of a training or validation metric ingested by the master.",
+	}, []string{"experiment_id", "trial_id", "metric_name"})
+	registry.MustRegister(gauge)
+
+	return &MetricsRegistry{
+		registry:     registry,
+		gauge:        gauge,
+		maxLabelSets: maxLabelSets,
+		influx:       influx,
+		lru:          list.New(),
+		index:        make(map[string]*list.Element),
+	}
+}
+
+// Gatherer exposes the registry for the /prom/metrics HTTP handler
+// (promhttp.HandlerFor(registry.Gatherer(), ...)).
+func (r *MetricsRegistry) Gatherer() prometheus.Gatherer {
+	return r.registry
+}
+
+// labelSet is what the LRU tracks per distinct (experiment_id, trial_id, metric_name): the
+// cache key used by index, and the label values needed to delete the gauge on eviction.
+type labelSet struct {
+	key    string
+	labels prometheus.Labels
+}
+
+// Observe records the latest value of a single metric for a trial, evicting the
+// least-recently-written label set if this observation would put the registry over its
+// cardinality cap, and pushes the same sample to InfluxDB if a pusher was configured.
+func (r *MetricsRegistry) Observe(experimentID, trialID int, metricName string, value float64) {
+	key := labelKey(experimentID, trialID, metricName)
+	labels := prometheus.Labels{
+		"experiment_id": fmt.Sprint(experimentID),
+		"trial_id":      fmt.Sprint(trialID),
+		"metric_name":   metricName,
+	}
+
+	r.mu.Lock()
+	if elem, ok := r.index[key]; ok {
+		r.lru.MoveToBack(elem)
+	} else {
+		if r.lru.Len() >= r.maxLabelSets {
+			r.evictOldestLocked()
+		}
+		r.index[key] = r.lru.PushBack(labelSet{key: key, labels: labels})
+	}
+	// Set under r.mu so a concurrent eviction of this label set can't run between tracking it
+	// and setting it, which would leave a gauge series the LRU no longer tracks or evicts.
+	r.gauge.With(labels).Set(value)
+	r.mu.Unlock()
+
+	if r.influx != nil {
+		line := fmt.Sprintf(
+			"determined_trial_metric,experiment_id=%d,trial_id=%d,metric_name=%s value=%v",
+			experimentID, trialID, escapeLineProtocolTagValue(metricName), value)
+		if err := r.influx.Push(line); err != nil {
+			// Best-effort: Prometheus scraping is the primary integration, so a push failure
+			// is logged rather than treated as an ingestion error.
+			log.Warnf("error pushing trial metric to influxdb: %v", err)
+		}
+	}
+}
+
+// evictOldestLocked removes the least-recently-written label set to make room for a new one.
+// Callers must hold r.mu.
+func (r *MetricsRegistry) evictOldestLocked() {
+	front := r.lru.Front()
+	if front == nil {
+		return
+	}
+	set := front.Value.(labelSet)
+	r.lru.Remove(front)
+	delete(r.index, set.key)
+	r.gauge.Delete(set.labels)
+}
+
+func labelKey(experimentID, trialID int, metricName string) string {
+	return fmt.Sprintf("%d/%d/%s", experimentID, trialID, metricName)
+}
+
+// lineProtocolTagReplacer escapes the characters InfluxDB line protocol requires to be escaped
+// in a tag key or value: a comma or space would otherwise be parsed as a field/tag separator,
+// and an equals sign as a key=value separator, silently truncating or corrupting the tag.
+var lineProtocolTagReplacer = strings.NewReplacer(
+	`,`, `\,`,
+	` `, `\ `,
+	`=`, `\=`,
+)
+
+// escapeLineProtocolTagValue escapes a tag value (here, a user-chosen metric name) for safe
+// inclusion in an InfluxDB line protocol line.
+func escapeLineProtocolTagValue(value string) string {
+	return lineProtocolTagReplacer.Replace(value)
+}
@@ -0,0 +1,68 @@
+package prom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsRegistryObserveUnderCapKeepsAllLabelSets(t *testing.T) {
+	r := NewMetricsRegistry(3, nil)
+
+	r.Observe(1, 1, "loss", 0.1)
+	r.Observe(1, 2, "loss", 0.2)
+
+	require.Equal(t, 2, r.lru.Len())
+	assert.Len(t, r.index, 2)
+}
+
+func TestMetricsRegistryEvictsLeastRecentlyWritten(t *testing.T) {
+	r := NewMetricsRegistry(2, nil)
+
+	r.Observe(1, 1, "loss", 0.1) // oldest
+	r.Observe(1, 2, "loss", 0.2)
+	r.Observe(1, 3, "loss", 0.3) // over cap: evicts trial 1's loss
+
+	require.Equal(t, 2, r.lru.Len())
+	_, ok := r.index[labelKey(1, 1, "loss")]
+	assert.False(t, ok, "least-recently-written label set should have been evicted")
+	_, ok = r.index[labelKey(1, 2, "loss")]
+	assert.True(t, ok)
+	_, ok = r.index[labelKey(1, 3, "loss")]
+	assert.True(t, ok)
+}
+
+func TestMetricsRegistryReobservingRefreshesRecency(t *testing.T) {
+	r := NewMetricsRegistry(2, nil)
+
+	r.Observe(1, 1, "loss", 0.1)
+	r.Observe(1, 2, "loss", 0.2)
+	r.Observe(1, 1, "loss", 0.15) // re-observed: now the most-recently-written, not the oldest
+	r.Observe(1, 3, "loss", 0.3)  // over cap: should evict trial 2's loss, not trial 1's
+
+	require.Equal(t, 2, r.lru.Len())
+	_, ok := r.index[labelKey(1, 2, "loss")]
+	assert.False(t, ok, "trial 2's label set should have been evicted instead of trial 1's")
+	_, ok = r.index[labelKey(1, 1, "loss")]
+	assert.True(t, ok)
+	_, ok = r.index[labelKey(1, 3, "loss")]
+	assert.True(t, ok)
+}
+
+func TestMetricsRegistryObservePushesEscapedLineToInflux(t *testing.T) {
+	pushed := make(chan string, 1)
+	r := NewMetricsRegistry(10, influxPusherFunc(func(line string) error {
+		pushed <- line
+		return nil
+	}))
+
+	r.Observe(1, 1, "val loss", 0.5)
+
+	line := <-pushed
+	assert.Contains(t, line, `metric_name=val\ loss`)
+}
+
+type influxPusherFunc func(line string) error
+
+func (f influxPusherFunc) Push(line string) error { return f(line) }
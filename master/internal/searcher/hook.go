@@ -0,0 +1,45 @@
+// Package searcher lets the master drive searcher decisions (ASHA/PBT/median-stopping, or an
+// external hyperparameter service) directly off validation writes, instead of the searcher
+// polling the database on a timer for new metrics.
+package searcher
+
+import "fmt"
+
+// Action is what a Hook decides a trial should do after reporting a validation metric.
+type Action int
+
+const (
+	// Continue leaves the trial running unchanged.
+	Continue Action = iota
+	// EarlyStop tells the trial to stop training; it has been judged unlikely to improve
+	// relative to its peers.
+	EarlyStop
+	// PromoteCheckpoint tells the trial's current checkpoint should be promoted, e.g. as the
+	// best checkpoint found so far or as a parent for the next round of a population-based
+	// search.
+	PromoteCheckpoint
+)
+
+func (a Action) String() string {
+	switch a {
+	case Continue:
+		return "continue"
+	case EarlyStop:
+		return "early_stop"
+	case PromoteCheckpoint:
+		return "promote_checkpoint"
+	default:
+		return fmt.Sprintf("unknown searcher action %d", int(a))
+	}
+}
+
+// Hook is invoked synchronously after a validation metric is persisted, in place of the
+// searcher polling the database on a timer. Implementations must return promptly: the call to
+// CreateValidationMetrics blocks on it, so a slow or hanging hook (e.g. an unresponsive
+// webhook) directly slows down trial harnesses.
+type Hook interface {
+	// OnValidation is called with the trial, how many batches it has completed, and the value
+	// of the experiment's configured searcher metric for this validation, and returns what
+	// the trial should do next.
+	OnValidation(trialID, totalBatches int, searcherMetricValue float64) (Action, error)
+}
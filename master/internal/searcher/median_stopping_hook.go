@@ -0,0 +1,94 @@
+package searcher
+
+import (
+	"sort"
+	"sync"
+)
+
+// MedianStoppingHook implements the median stopping rule: a trial is stopped early once its
+// searcher metric, at a given number of completed batches, is worse than the median of all
+// other trials' best metric reported by that same point. It needs no external service and no
+// per-trial state beyond what it tracks itself.
+type MedianStoppingHook struct {
+	// SmallerIsBetter matches the experiment's searcher metric direction: true for a loss,
+	// false for an accuracy-like metric.
+	SmallerIsBetter bool
+	// MinTrials is how many other trials must have already reported a value at a given
+	// totalBatches before a new trial can be stopped against their median. Below this, there
+	// isn't enough of a baseline to judge against.
+	MinTrials int
+
+	mu      sync.Mutex
+	history map[int]map[int]float64 // totalBatches -> trialID -> best metric reported so far
+	best    map[int]float64         // trialID -> best metric reported so far
+}
+
+// NewMedianStoppingHook returns a MedianStoppingHook ready for use.
+func NewMedianStoppingHook(smallerIsBetter bool, minTrials int) *MedianStoppingHook {
+	if minTrials <= 0 {
+		minTrials = 3
+	}
+	return &MedianStoppingHook{
+		SmallerIsBetter: smallerIsBetter,
+		MinTrials:       minTrials,
+		history:         make(map[int]map[int]float64),
+		best:            make(map[int]float64),
+	}
+}
+
+// OnValidation updates the trial's running-best metric, records it against the given
+// totalBatches, and stops the trial if that running-best is worse than the median of the
+// *other* trials recorded at the same totalBatches. Recording one value per (trialID,
+// totalBatches) rather than appending means a trial that validates more than once at the same
+// totalBatches only ever contributes its latest running-best to the peer median, instead of
+// being counted once per validation.
+func (h *MedianStoppingHook) OnValidation(
+	trialID, totalBatches int, searcherMetricValue float64,
+) (Action, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if best, ok := h.best[trialID]; !ok || h.isBetter(searcherMetricValue, best) {
+		h.best[trialID] = searcherMetricValue
+	}
+	running := h.best[trialID]
+
+	if h.history[totalBatches] == nil {
+		h.history[totalBatches] = make(map[int]float64)
+	}
+	h.history[totalBatches][trialID] = running
+
+	peers := make([]float64, 0, len(h.history[totalBatches]))
+	for peerTrialID, value := range h.history[totalBatches] {
+		if peerTrialID == trialID {
+			continue
+		}
+		peers = append(peers, value)
+	}
+	if len(peers) < h.MinTrials {
+		return Continue, nil
+	}
+
+	median := medianOf(peers)
+	if h.isBetter(median, running) {
+		return EarlyStop, nil
+	}
+	return Continue, nil
+}
+
+func (h *MedianStoppingHook) isBetter(a, b float64) bool {
+	if h.SmallerIsBetter {
+		return a < b
+	}
+	return a > b
+}
+
+func medianOf(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,97 @@
+package searcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMedianStoppingHookBelowMinTrialsAlwaysContinues(t *testing.T) {
+	hook := NewMedianStoppingHook(true, 3)
+
+	action, err := hook.OnValidation(1, 100, 0.5)
+	require.NoError(t, err)
+	require.Equal(t, Continue, action)
+
+	action, err = hook.OnValidation(2, 100, 0.5)
+	require.NoError(t, err)
+	require.Equal(t, Continue, action)
+}
+
+func TestMedianStoppingHookSmallerIsBetterStopsWorseTrial(t *testing.T) {
+	hook := NewMedianStoppingHook(true /* smallerIsBetter */, 2)
+
+	// Two peers establish a median of 1.0 (trials 2 and 3), then a third trial reporting a
+	// clearly worse (higher) loss should be stopped.
+	_, err := hook.OnValidation(2, 100, 1.0)
+	require.NoError(t, err)
+	_, err = hook.OnValidation(3, 100, 1.0)
+	require.NoError(t, err)
+
+	action, err := hook.OnValidation(1, 100, 10.0)
+	require.NoError(t, err)
+	require.Equal(t, EarlyStop, action)
+}
+
+func TestMedianStoppingHookSmallerIsBetterContinuesBetterTrial(t *testing.T) {
+	hook := NewMedianStoppingHook(true, 2)
+
+	_, err := hook.OnValidation(2, 100, 1.0)
+	require.NoError(t, err)
+	_, err = hook.OnValidation(3, 100, 1.0)
+	require.NoError(t, err)
+
+	action, err := hook.OnValidation(1, 100, 0.1)
+	require.NoError(t, err)
+	require.Equal(t, Continue, action)
+}
+
+func TestMedianStoppingHookLargerIsBetterStopsWorseTrial(t *testing.T) {
+	hook := NewMedianStoppingHook(false /* smallerIsBetter */, 2)
+
+	_, err := hook.OnValidation(2, 100, 0.9)
+	require.NoError(t, err)
+	_, err = hook.OnValidation(3, 100, 0.9)
+	require.NoError(t, err)
+
+	action, err := hook.OnValidation(1, 100, 0.1)
+	require.NoError(t, err)
+	require.Equal(t, EarlyStop, action)
+}
+
+func TestMedianStoppingHookExcludesSelfFromMedian(t *testing.T) {
+	// Trial 1's 2.5 is worse than the median of its peers (2.0, from trials 2 and 3), so it is
+	// stopped. If its own value counted, the median of {1.0, 2.5, 3.0} would be 2.5, its own
+	// value, which is not better than itself, and the trial would continue.
+	hook := NewMedianStoppingHook(true, 1)
+
+	_, err := hook.OnValidation(2, 100, 1.0)
+	require.NoError(t, err)
+	_, err = hook.OnValidation(3, 100, 3.0)
+	require.NoError(t, err)
+
+	action, err := hook.OnValidation(1, 100, 2.5)
+	require.NoError(t, err)
+	require.Equal(t, EarlyStop, action)
+}
+
+func TestMedianStoppingHookDedupesRepeatValidationsAtSameTotalBatches(t *testing.T) {
+	hook := NewMedianStoppingHook(true, 2)
+
+	// Trial 2 validates five times at the same totalBatches without ever improving; it must
+	// only ever contribute one entry (its running-best of 100) to the peer pool, not five. If
+	// it instead contributed one entry per validation, the five 100s would drag the peer
+	// median up to 100 and trial 1's 50 would look better than the median (Continue). Deduped
+	// to a single 100, trial 3's single 1 pulls the median down to 50.5 and trial 1's 50 looks
+	// worse than the median (EarlyStop).
+	for i := 0; i < 5; i++ {
+		_, err := hook.OnValidation(2, 100, 100.0)
+		require.NoError(t, err)
+	}
+	_, err := hook.OnValidation(3, 100, 1.0)
+	require.NoError(t, err)
+
+	action, err := hook.OnValidation(1, 100, 50.0)
+	require.NoError(t, err)
+	require.Equal(t, EarlyStop, action)
+}
@@ -0,0 +1,92 @@
+package searcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WebhookHook forwards each validation to an external hyperparameter service (Optuna, Katib,
+// SigOpt, ...) over HTTP and maps its response back to an Action. It lets those services drive
+// early stopping/checkpoint promotion decisions without Determined needing a built-in
+// integration for each one.
+type WebhookHook struct {
+	// URL is the endpoint the hook POSTs each validation to.
+	URL string
+	// Timeout bounds how long a single request may take; OnValidation blocks the ingestion
+	// RPC, so this should be kept well under the harness's own RPC timeout.
+	Timeout time.Duration
+
+	client *http.Client
+}
+
+// NewWebhookHook returns a WebhookHook posting to url with the given timeout. A timeout <= 0
+// defaults to 5 seconds.
+func NewWebhookHook(url string, timeout time.Duration) *WebhookHook {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &WebhookHook{
+		URL:     url,
+		Timeout: timeout,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+type webhookRequest struct {
+	TrialID             int     `json:"trial_id"`
+	TotalBatches        int     `json:"total_batches"`
+	SearcherMetricValue float64 `json:"searcher_metric_value"`
+}
+
+type webhookResponse struct {
+	Action string `json:"action"`
+}
+
+// OnValidation POSTs the validation to the configured URL and maps its `action` field
+// ("continue", "early_stop", or "promote_checkpoint") to an Action. A response naming an
+// unrecognized action, or a request that fails outright, falls back to Continue along with the
+// error: a misbehaving external service should never stop trials it did not clearly ask to
+// stop.
+func (h *WebhookHook) OnValidation(
+	trialID, totalBatches int, searcherMetricValue float64,
+) (Action, error) {
+	body, err := json.Marshal(webhookRequest{
+		TrialID:             trialID,
+		TotalBatches:        totalBatches,
+		SearcherMetricValue: searcherMetricValue,
+	})
+	if err != nil {
+		return Continue, errors.Wrap(err, "error marshaling searcher webhook request")
+	}
+
+	resp, err := h.client.Post(h.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return Continue, errors.Wrap(err, "error calling searcher webhook")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Continue, errors.Errorf("searcher webhook returned status %d", resp.StatusCode)
+	}
+
+	var parsed webhookResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return Continue, errors.Wrap(err, "error decoding searcher webhook response")
+	}
+
+	switch parsed.Action {
+	case "continue", "":
+		return Continue, nil
+	case "early_stop":
+		return EarlyStop, nil
+	case "promote_checkpoint":
+		return PromoteCheckpoint, nil
+	default:
+		return Continue, fmt.Errorf("searcher webhook returned unrecognized action %q", parsed.Action)
+	}
+}
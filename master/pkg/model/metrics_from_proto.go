@@ -0,0 +1,113 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+
+	"github.com/determined-ai/determined/proto/pkg/apiv1"
+)
+
+// TrainingMetrics is the model representation of a trial's training metrics for one step,
+// as stored by db.AddStep/db.UpdateStep.
+type TrainingMetrics struct {
+	UUID         uuid.UUID
+	TrialID      int
+	StartBatch   int
+	EndBatch     int
+	State        string
+	Metrics      map[string]float64
+	BatchMetrics []map[string]interface{}
+}
+
+// ValidationMetrics is the model representation of a trial's validation metrics for one
+// validation, as stored by db.AddValidation/db.UpdateValidation.
+type ValidationMetrics struct {
+	UUID         uuid.UUID
+	TrialID      int
+	TotalBatches int
+	State        string
+	Metrics      map[string]float64
+}
+
+// TrainingMetricsFromProto validates and converts an apiv1.TrainingMetrics into a
+// TrainingMetrics. Every problem it finds - a malformed UUID, a negative or out-of-order batch
+// index, a non-finite metric value, or a batch_metrics blob that isn't valid JSON - is
+// collected into a single *MetricsValidationError rather than returned as soon as the first
+// one is found, so a caller fixing its submission sees every problem at once instead of
+// re-submitting once per field.
+func TrainingMetricsFromProto(p *apiv1.TrainingMetrics) (*TrainingMetrics, error) {
+	validationErr := &MetricsValidationError{}
+
+	id, err := uuid.Parse(p.Uuid)
+	if err != nil {
+		validationErr.Add("uuid", fmt.Sprintf("invalid uuid %q: %s", p.Uuid, err))
+	}
+	if p.StartBatch < 0 {
+		validationErr.Add("start_batch", "must be non-negative")
+	}
+	if p.EndBatch < p.StartBatch {
+		validationErr.Add("end_batch", "must be greater than or equal to start_batch")
+	}
+	validateMetricValues(p.Metrics, validationErr)
+
+	var batchMetrics []map[string]interface{}
+	if len(p.BatchMetrics) > 0 {
+		if err := json.Unmarshal(p.BatchMetrics, &batchMetrics); err != nil {
+			validationErr.Add("batch_metrics", fmt.Sprintf("invalid JSON: %s", err))
+		}
+	}
+
+	if validationErr.HasErrors() {
+		return nil, validationErr
+	}
+
+	return &TrainingMetrics{
+		UUID:         id,
+		TrialID:      int(p.TrialId),
+		StartBatch:   int(p.StartBatch),
+		EndBatch:     int(p.EndBatch),
+		State:        p.State,
+		Metrics:      p.Metrics,
+		BatchMetrics: batchMetrics,
+	}, nil
+}
+
+// ValidationMetricsFromProto is the TrainingMetricsFromProto counterpart for validation
+// metrics; see its doc comment for the classification rules shared by both.
+func ValidationMetricsFromProto(p *apiv1.ValidationMetrics) (*ValidationMetrics, error) {
+	validationErr := &MetricsValidationError{}
+
+	id, err := uuid.Parse(p.Uuid)
+	if err != nil {
+		validationErr.Add("uuid", fmt.Sprintf("invalid uuid %q: %s", p.Uuid, err))
+	}
+	if p.TotalBatches < 0 {
+		validationErr.Add("total_batches", "must be non-negative")
+	}
+	validateMetricValues(p.Metrics, validationErr)
+
+	if validationErr.HasErrors() {
+		return nil, validationErr
+	}
+
+	return &ValidationMetrics{
+		UUID:         id,
+		TrialID:      int(p.TrialId),
+		TotalBatches: int(p.TotalBatches),
+		State:        p.State,
+		Metrics:      p.Metrics,
+	}, nil
+}
+
+// validateMetricValues flags any NaN or +/-Inf metric value, which a DB numeric column can't
+// store and which would otherwise surface much later as an opaque database error.
+func validateMetricValues(metrics map[string]float64, validationErr *MetricsValidationError) {
+	for name, value := range metrics {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			validationErr.Add(fmt.Sprintf("metrics[%s]", name), "must be a finite number")
+		}
+	}
+}
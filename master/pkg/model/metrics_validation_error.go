@@ -0,0 +1,51 @@
+package model
+
+import "fmt"
+
+// FieldError names a single field that failed validation and the reason why, so a caller can
+// tell a malformed UUID apart from a negative batch index or a NaN metric value instead of
+// parsing a single opaque error string.
+type FieldError struct {
+	FieldName string
+	Reason    string
+}
+
+func (e FieldError) String() string {
+	return fmt.Sprintf("%s: %s", e.FieldName, e.Reason)
+}
+
+// MetricsValidationError collects the FieldErrors found while converting a training or
+// validation metrics proto to its model representation. Every FieldError it carries is a
+// user error: the submission itself is malformed (bad UUID, negative batch index, NaN/Inf
+// metric value, unparsable nested metrics map) and retrying the same payload will never
+// succeed. Failures that are instead the server's fault (e.g. the DB write itself failing)
+// are never wrapped in a MetricsValidationError; callers can use errors.As to tell the two
+// apart and only auto-retry the latter.
+type MetricsValidationError struct {
+	Errors []FieldError
+}
+
+func (e *MetricsValidationError) Error() string {
+	switch len(e.Errors) {
+	case 0:
+		return "metrics validation failed"
+	case 1:
+		return fmt.Sprintf("metrics validation failed: %s", e.Errors[0])
+	default:
+		msg := fmt.Sprintf("metrics validation failed with %d errors:", len(e.Errors))
+		for _, fe := range e.Errors {
+			msg += fmt.Sprintf("\n  - %s", fe)
+		}
+		return msg
+	}
+}
+
+// Add appends a field error to e.
+func (e *MetricsValidationError) Add(fieldName, reason string) {
+	e.Errors = append(e.Errors, FieldError{FieldName: fieldName, Reason: reason})
+}
+
+// HasErrors reports whether any field errors have been collected.
+func (e *MetricsValidationError) HasErrors() bool {
+	return e != nil && len(e.Errors) > 0
+}